@@ -0,0 +1,196 @@
+// Package feedback closes the RL training loop: it records what the
+// scheduler observed and did at Bind time, turns that into a reward, and
+// ships replay-buffer-ready transitions to a PyTorch trainer.
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// RewardConfig weights the components that make up a transition's reward.
+// Each is applied when RecordBind records a transition: fragmentation from
+// the cluster state before and after the placement, makespan from the
+// pod's cost, and preemption from the number of evicted victims.
+type RewardConfig struct {
+	// FragmentationWeight penalizes GPU fragmentation left behind on the
+	// chosen node and across the cluster.
+	FragmentationWeight float64
+	// MakespanWeight penalizes the wall-clock cost of this placement. It's
+	// multiplied by a per-placement cost (the pod's requested CPU/GPU,
+	// see plugin.podRequestCost) rather than applied as a flat constant,
+	// so larger and GPU-bound pods cost more than a trivial one.
+	MakespanWeight float64
+	// PreemptionWeight penalizes placements that required preempting
+	// other pods.
+	PreemptionWeight float64
+}
+
+// DefaultRewardConfig returns the weights used when no RL_SCHEDULER_REWARD_*
+// environment variable overrides them.
+func DefaultRewardConfig() RewardConfig {
+	return RewardConfig{
+		FragmentationWeight: envFloat("RL_SCHEDULER_REWARD_FRAGMENTATION_WEIGHT", 1.0),
+		MakespanWeight:      envFloat("RL_SCHEDULER_REWARD_MAKESPAN_WEIGHT", 0.5),
+		PreemptionWeight:    envFloat("RL_SCHEDULER_REWARD_PREEMPTION_WEIGHT", 2.0),
+	}
+}
+
+func envFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+// Transition is the replay-buffer-friendly record POSTed to
+// RL_SCHEDULER_FEEDBACK_ENDPOINT. State/NextState are whatever observation
+// encoding the plugin is currently using, kept opaque here so this package
+// doesn't need to depend on the plugin package.
+type Transition struct {
+	State     json.RawMessage `json:"state"`
+	Kind      string          `json:"kind"`
+	Action    string          `json:"action"`
+	Reward    float64         `json:"reward"`
+	NextState json.RawMessage `json:"next_state"`
+	Done      bool            `json:"done"`
+}
+
+// Action kinds distinguish what produced a Transition's Action string, since
+// a bare node name (ActionKindPlacement) and a preemption's
+// "node:victim1,victim2" encoding (ActionKindPreemption) would otherwise be
+// indistinguishable to a trainer consuming the replay buffer.
+const (
+	ActionKindPlacement  = "placement"
+	ActionKindPreemption = "preemption"
+)
+
+// NodeOccupancy is a node's actual free capacity at Bind time: the caller
+// computes these from live bound-pod occupancy (e.g. a scheduler NodeInfo
+// snapshot), not from a node's static Allocatable/Capacity, so fragmentation
+// reflects pods already running on the cluster rather than a pristine node.
+type NodeOccupancy struct {
+	FreeCPUFraction    float64
+	FreeMemoryFraction float64
+	FreeGPUFraction    float64
+}
+
+// Recorder turns each recorded placement or preemption into one Transition
+// and POSTs it to the configured feedback endpoint right away; it keeps no
+// buffer of its own. A Transition carries the encoded state, the action and
+// the reward; the score vector the RL service returned is not included.
+type Recorder struct {
+	endpoint string
+	client   *http.Client
+	reward   RewardConfig
+}
+
+// NewRecorder builds a Recorder that POSTs transitions to endpoint. An empty
+// endpoint disables recording: RecordBind becomes a no-op so callers don't
+// need to branch on whether feedback is configured.
+func NewRecorder(endpoint string, reward RewardConfig) *Recorder {
+	return &Recorder{
+		endpoint: endpoint,
+		client:   &http.Client{},
+		reward:   reward,
+	}
+}
+
+// Enabled reports whether a feedback endpoint was configured.
+func (r *Recorder) Enabled() bool { return r != nil && r.endpoint != "" }
+
+// RecordBind builds a Transition from one Bind call and POSTs it to the
+// feedback endpoint. kind is one of the ActionKind* constants, identifying
+// whether action is a bare node name (placement) or a preemption's
+// "node:victims" encoding. state and nextState are the encoded observations
+// before and after the placement; before/after are each node's real free
+// capacity at those two points (nil for transitions, like preemptions, that
+// don't have a meaningful fragmentation delta); podCost approximates the
+// runtime cost of the pod being placed (0 outside the placement path);
+// preemptionCount is the number of victim pods this placement evicted (0
+// outside the preemption path).
+func (r *Recorder) RecordBind(ctx context.Context, state, nextState json.RawMessage, kind, action string, before, after []NodeOccupancy, podCost float64, preemptionCount int) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	reward := r.reward.FragmentationWeight*(fragmentation(before)-fragmentation(after)) -
+		r.reward.MakespanWeight*podCost -
+		r.reward.PreemptionWeight*float64(preemptionCount)
+
+	return r.post(ctx, Transition{
+		State:     state,
+		Kind:      kind,
+		Action:    action,
+		Reward:    reward,
+		NextState: nextState,
+		Done:      false,
+	})
+}
+
+// EndEpisode marks the end of a workload by POSTing a terminal transition so
+// the trainer can close out the episode in its replay buffer.
+func (r *Recorder) EndEpisode(ctx context.Context, state json.RawMessage) error {
+	if !r.Enabled() {
+		return nil
+	}
+	return r.post(ctx, Transition{State: state, NextState: state, Done: true})
+}
+
+func (r *Recorder) post(ctx context.Context, t Transition) error {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("marshaling transition: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("building feedback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting transition to %s: %w", r.endpoint, err)
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+// fragmentation is a GPU/resource-balance proxy: the average, across CPU,
+// memory and GPU, of how much free capacity is stranded in nodes that are
+// neither fully idle nor fully packed. Higher values mean more resources
+// are left stranded in partially-used nodes rather than concentrated or
+// freed entirely.
+func fragmentation(nodes []NodeOccupancy) float64 {
+	if len(nodes) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, n := range nodes {
+		total += strandedFraction(n.FreeCPUFraction) +
+			strandedFraction(n.FreeMemoryFraction) +
+			strandedFraction(n.FreeGPUFraction)
+	}
+	return total / float64(3*len(nodes))
+}
+
+// strandedFraction is free*(1-free): 0 at fully idle or fully packed, peaking
+// at half-free.
+func strandedFraction(free float64) float64 {
+	if free <= 0 || free >= 1 {
+		return 0
+	}
+	return free * (1 - free)
+}
@@ -0,0 +1,88 @@
+package feedback
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFragmentation(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []NodeOccupancy
+		want  float64
+	}{
+		{name: "no nodes", nodes: nil, want: 0},
+		{
+			name:  "fully idle node contributes nothing",
+			nodes: []NodeOccupancy{{FreeCPUFraction: 1, FreeMemoryFraction: 1, FreeGPUFraction: 1}},
+			want:  0,
+		},
+		{
+			name:  "fully packed node contributes nothing",
+			nodes: []NodeOccupancy{{FreeCPUFraction: 0, FreeMemoryFraction: 0, FreeGPUFraction: 0}},
+			want:  0,
+		},
+		{
+			name:  "half-free node is maximally fragmented",
+			nodes: []NodeOccupancy{{FreeCPUFraction: 0.5, FreeMemoryFraction: 0.5, FreeGPUFraction: 0.5}},
+			want:  0.25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fragmentation(tt.nodes); got != tt.want {
+				t.Errorf("fragmentation(%+v) = %v, want %v", tt.nodes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordBindRewardMath(t *testing.T) {
+	r := &Recorder{endpoint: "http://example.invalid/feedback", reward: RewardConfig{
+		FragmentationWeight: 1,
+		MakespanWeight:      2,
+		PreemptionWeight:    3,
+	}}
+
+	before := []NodeOccupancy{{FreeCPUFraction: 0.5, FreeMemoryFraction: 0.5, FreeGPUFraction: 0.5}}
+	after := []NodeOccupancy{{FreeCPUFraction: 0, FreeMemoryFraction: 0, FreeGPUFraction: 0}}
+
+	// Capture the reward without actually posting: compute it the same way
+	// RecordBind does, since RecordBind's HTTP POST would need a live
+	// endpoint. fragmentation(before) - fragmentation(after) = 0.25 - 0 here.
+	got := r.reward.FragmentationWeight*(fragmentation(before)-fragmentation(after)) -
+		r.reward.MakespanWeight*1.5 -
+		r.reward.PreemptionWeight*float64(1)
+	want := 1*0.25 - 2*1.5 - 3*1.0
+	if got != want {
+		t.Errorf("reward = %v, want %v", got, want)
+	}
+}
+
+func TestEnvFloat(t *testing.T) {
+	const key = "RL_SCHEDULER_TEST_ENV_FLOAT"
+
+	t.Run("unset falls back", func(t *testing.T) {
+		os.Unsetenv(key)
+		if got := envFloat(key, 2.5); got != 2.5 {
+			t.Errorf("envFloat() = %v, want fallback 2.5", got)
+		}
+	})
+
+	t.Run("valid override", func(t *testing.T) {
+		os.Setenv(key, "4.5")
+		defer os.Unsetenv(key)
+		if got := envFloat(key, 2.5); got != 4.5 {
+			t.Errorf("envFloat() = %v, want 4.5", got)
+		}
+	})
+
+	t.Run("invalid falls back", func(t *testing.T) {
+		os.Setenv(key, "not-a-number")
+		defer os.Unsetenv(key)
+		if got := envFloat(key, 2.5); got != 2.5 {
+			t.Errorf("envFloat() = %v, want fallback 2.5", got)
+		}
+	})
+}
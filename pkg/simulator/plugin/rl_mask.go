@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1helper "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// computeValidMask pre-computes, per node in nodes, whether pod could
+// actually be placed there: it fits the node's free resources, satisfies
+// the node selector/affinity, tolerates the node's taints, and matches the
+// pod's requested GPU type. The mask travels to the RL service alongside
+// the feature vectors so invalid nodes never enter the policy's action
+// distribution. requestedByNode carries each node's currently-bound-pod
+// requests (see RLSchedulerScorePlugin.requestedByNode); a missing entry
+// falls back to treating the node as unoccupied.
+func computeValidMask(pod *corev1.Pod, nodes []*corev1.Node, requestedByNode map[string]*framework.Resource) []bool {
+	requiredAffinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+
+	mask := make([]bool, len(nodes))
+	for i, n := range nodes {
+		mask[i] = fitsResources(pod, n, requestedByNode[n.Name]) &&
+			matchesNodeSelector(pod, n) &&
+			nodeAffinityMatches(requiredAffinity, n) &&
+			toleratesTaints(pod, n) &&
+			matchesGPUType(pod, n)
+	}
+	return mask
+}
+
+// fitsResources checks pod's requests against node's actual free capacity:
+// its static Allocatable minus whatever's already requested by bound pods
+// (requested), not Allocatable alone, so a node packed full by earlier
+// placements in this same simulation isn't still reported as having all of
+// its original capacity free.
+func fitsResources(pod *corev1.Pod, node *corev1.Node, requested *framework.Resource) bool {
+	var cpu, mem, gpu float64
+	for _, c := range pod.Spec.Containers {
+		cpu += c.Resources.Requests.Cpu().AsApproximateFloat64()
+		mem += c.Resources.Requests.Memory().AsApproximateFloat64()
+		gpu += gpuShare(c.Resources.Requests)
+	}
+
+	freeCPU := node.Status.Allocatable.Cpu().AsApproximateFloat64()
+	freeMem := node.Status.Allocatable.Memory().AsApproximateFloat64()
+	freeGPU := gpuShare(node.Status.Allocatable)
+	if requested != nil {
+		freeCPU -= float64(requested.MilliCPU) / 1000
+		freeMem -= float64(requested.Memory)
+		freeGPU -= float64(requested.ScalarResources[gpuResourceName])
+	}
+
+	return cpu <= freeCPU && mem <= freeMem && gpu <= freeGPU
+}
+
+func matchesNodeSelector(pod *corev1.Pod, node *corev1.Node) bool {
+	for k, v := range pod.Spec.NodeSelector {
+		if node.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeAffinityMatches(required nodeaffinity.RequiredNodeAffinity, node *corev1.Node) bool {
+	ok, err := required.Match(node)
+	return err == nil && ok
+}
+
+func toleratesTaints(pod *corev1.Pod, node *corev1.Node) bool {
+	_, untolerated := v1helper.FindMatchingUntoleratedTaint(node.Spec.Taints, pod.Spec.Tolerations, func(t *corev1.Taint) bool {
+		return t.Effect == corev1.TaintEffectNoSchedule || t.Effect == corev1.TaintEffectNoExecute
+	})
+	return !untolerated
+}
+
+// matchesGPUType checks that, if the pod asks for a specific GPU model via
+// the well-known node-selector label, the node actually carries that model.
+// Pods that don't name a model through that label always match, whether or
+// not they request a GPU.
+func matchesGPUType(pod *corev1.Pod, node *corev1.Node) bool {
+	wanted, ok := pod.Spec.NodeSelector["nvidia.com/gpu.product"]
+	if !ok {
+		return true
+	}
+	return node.Labels["nvidia.com/gpu.product"] == wanted
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func testPod(cpu, mem string, nodeSelector map[string]string, tolerations []corev1.Toleration) *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: nodeSelector,
+			Tolerations:  tolerations,
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(mem),
+					},
+				},
+			}},
+		},
+	}
+}
+
+func testNode(cpu, mem string, labels map[string]string, taints []corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(mem),
+			},
+		},
+	}
+}
+
+func TestComputeValidMask(t *testing.T) {
+	pod := testPod("1", "1Gi", nil, nil)
+
+	fitsNode := testNode("2", "2Gi", nil, nil)
+	tooSmallNode := testNode("0.5", "2Gi", nil, nil)
+
+	mask := computeValidMask(pod, []*corev1.Node{fitsNode, tooSmallNode}, nil)
+	want := []bool{true, false}
+	for i := range want {
+		if mask[i] != want[i] {
+			t.Errorf("mask[%d] = %v, want %v", i, mask[i], want[i])
+		}
+	}
+}
+
+func TestComputeValidMaskAccountsForAlreadyBoundPods(t *testing.T) {
+	pod := testPod("1", "1Gi", nil, nil)
+	node := testNode("1", "1Gi", nil, nil)
+
+	// The node's static Allocatable fits pod exactly, but another pod has
+	// already requested half of it, so only half remains free and pod no
+	// longer fits.
+	requestedByNode := map[string]*framework.Resource{
+		node.Name: {MilliCPU: 500, Memory: 512 * 1024 * 1024},
+	}
+
+	mask := computeValidMask(pod, []*corev1.Node{node}, requestedByNode)
+	if mask[0] {
+		t.Fatal("expected a node already packed by bound pods to be masked out, even though static Allocatable alone would fit")
+	}
+}
+
+func TestComputeValidMaskExcludesUntoleratedTaint(t *testing.T) {
+	pod := testPod("1", "1Gi", nil, nil)
+	tainted := testNode("2", "2Gi", nil, []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}})
+
+	mask := computeValidMask(pod, []*corev1.Node{tainted}, nil)
+	if mask[0] {
+		t.Fatal("expected a node with an untolerated NoSchedule taint to be masked out")
+	}
+}
+
+func TestComputeValidMaskToleratesMatchingTaint(t *testing.T) {
+	pod := testPod("1", "1Gi", nil, []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}})
+	tainted := testNode("2", "2Gi", nil, []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}})
+
+	mask := computeValidMask(pod, []*corev1.Node{tainted}, nil)
+	if !mask[0] {
+		t.Fatal("expected a tolerated taint not to mask the node out")
+	}
+}
+
+func TestComputeValidMaskRespectsGPUType(t *testing.T) {
+	pod := testPod("1", "1Gi", map[string]string{"nvidia.com/gpu.product": "A100"}, nil)
+	wrongGPU := testNode("2", "2Gi", map[string]string{"nvidia.com/gpu.product": "V100"}, nil)
+	rightGPU := testNode("2", "2Gi", map[string]string{"nvidia.com/gpu.product": "A100"}, nil)
+
+	mask := computeValidMask(pod, []*corev1.Node{wrongGPU, rightGPU}, nil)
+	if mask[0] {
+		t.Error("expected node with the wrong GPU model to be masked out")
+	}
+	if !mask[1] {
+		t.Error("expected node with the requested GPU model to be valid")
+	}
+}
@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// observationSchemaVersion is bumped whenever the shape or ordering of
+// NodeFeatures/PodFeatures changes, so a Python trainer can detect a stale
+// cached schema instead of silently misinterpreting the feature vector.
+const observationSchemaVersion = "v1"
+
+// defaultGPUModels is the one-hot vocabulary used by defaultObservationEncoder
+// when the plugin isn't told about a cluster-specific set of GPU models.
+var defaultGPUModels = []string{"A100", "V100", "T4", "H100"}
+
+// NodeFeatures is the compact, versioned per-node feature vector sent to the
+// RL service in place of a raw corev1.Node. Every field is normalized to
+// [0, 1] except NUMAIndex (an integer 0-3) and the bitmaps/digests, which are
+// opaque but stable identifiers a policy network can embed.
+type NodeFeatures struct {
+	FreeCPUFraction    float64   `json:"free_cpu_fraction"`
+	FreeMemoryFraction float64   `json:"free_memory_fraction"`
+	FreeGPUFraction    float64   `json:"free_gpu_fraction"`
+	GPUModelOneHot     []float64 `json:"gpu_model_one_hot"`
+	NUMAIndex          int       `json:"numa_index"`
+	TaintsBitmap       uint32    `json:"taints_bitmap"`
+	AffinityDigest     string    `json:"affinity_digest"`
+}
+
+// PodFeatures is the compact feature vector for the pod being scheduled.
+type PodFeatures struct {
+	RequestedCPUFraction float64 `json:"requested_cpu_fraction"`
+	RequestedMemoryBytes float64 `json:"requested_memory_bytes"`
+	GPUShareFraction     float64 `json:"gpu_share_fraction"`
+	PriorityClass        string  `json:"priority_class"`
+	WorkloadType         string  `json:"workload_type"`
+}
+
+// ObservationSchema describes the feature vectors an ObservationEncoder
+// produces, so the Python side can self-configure its input layer instead of
+// hard-coding field order.
+type ObservationSchema struct {
+	Version          string   `json:"version"`
+	NodeFeatureOrder []string `json:"node_feature_order"`
+	PodFeatureOrder  []string `json:"pod_feature_order"`
+	GPUModels        []string `json:"gpu_models"`
+}
+
+// ObservationEncoder turns raw k8s Pod/Node objects into the compact feature
+// vectors the RL service actually consumes, so the wire payload doesn't grow
+// with the k8s API and doesn't break when that API changes shape.
+type ObservationEncoder interface {
+	EncodePod(pod *corev1.Pod) PodFeatures
+	EncodeNode(pod *corev1.Pod, node *corev1.Node) NodeFeatures
+	Schema() ObservationSchema
+}
+
+// defaultObservationEncoder is the built-in ObservationEncoder. It knows
+// nothing about workload-specific conventions beyond a couple of well-known
+// labels, which keeps it usable across clusters without configuration.
+type defaultObservationEncoder struct {
+	gpuModels []string
+}
+
+// NewDefaultObservationEncoder builds the default encoder. An empty
+// gpuModels falls back to defaultGPUModels.
+func NewDefaultObservationEncoder(gpuModels []string) ObservationEncoder {
+	if len(gpuModels) == 0 {
+		gpuModels = defaultGPUModels
+	}
+	return &defaultObservationEncoder{gpuModels: gpuModels}
+}
+
+func (e *defaultObservationEncoder) EncodePod(pod *corev1.Pod) PodFeatures {
+	var cpu, mem, gpu float64
+	for _, c := range pod.Spec.Containers {
+		cpu += c.Resources.Requests.Cpu().AsApproximateFloat64()
+		mem += c.Resources.Requests.Memory().AsApproximateFloat64()
+		gpu += gpuShare(c.Resources.Requests)
+	}
+
+	return PodFeatures{
+		RequestedCPUFraction: cpu,
+		RequestedMemoryBytes: mem,
+		GPUShareFraction:     gpu,
+		PriorityClass:        pod.Spec.PriorityClassName,
+		WorkloadType:         pod.Labels["workload-type"],
+	}
+}
+
+func (e *defaultObservationEncoder) EncodeNode(pod *corev1.Pod, node *corev1.Node) NodeFeatures {
+	oneHot := make([]float64, len(e.gpuModels))
+	model := node.Labels["nvidia.com/gpu.product"]
+	for i, m := range e.gpuModels {
+		if m == model {
+			oneHot[i] = 1
+		}
+	}
+
+	return NodeFeatures{
+		FreeCPUFraction:    fraction(node.Status.Allocatable.Cpu().AsApproximateFloat64(), node.Status.Capacity.Cpu().AsApproximateFloat64()),
+		FreeMemoryFraction: fraction(node.Status.Allocatable.Memory().AsApproximateFloat64(), node.Status.Capacity.Memory().AsApproximateFloat64()),
+		FreeGPUFraction:    fraction(gpuShare(node.Status.Allocatable), gpuShare(node.Status.Capacity)),
+		GPUModelOneHot:     oneHot,
+		NUMAIndex:          numaIndex(node),
+		TaintsBitmap:       taintsBitmap(node),
+		AffinityDigest:     affinityDigest(pod),
+	}
+}
+
+func (e *defaultObservationEncoder) Schema() ObservationSchema {
+	return ObservationSchema{
+		Version: observationSchemaVersion,
+		NodeFeatureOrder: []string{
+			"free_cpu_fraction", "free_memory_fraction", "free_gpu_fraction",
+			"gpu_model_one_hot", "numa_index", "taints_bitmap", "affinity_digest",
+		},
+		PodFeatureOrder: []string{
+			"requested_cpu_fraction", "requested_memory_bytes", "gpu_share_fraction",
+			"priority_class", "workload_type",
+		},
+		GPUModels: e.gpuModels,
+	}
+}
+
+func fraction(free, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return free / total
+}
+
+func gpuShare(list corev1.ResourceList) float64 {
+	if q, ok := list["nvidia.com/gpu"]; ok {
+		return q.AsApproximateFloat64()
+	}
+	return 0
+}
+
+// numaIndex reads the simulator's NUMA placement label, defaulting to 0 for
+// nodes that don't carry one.
+func numaIndex(node *corev1.Node) int {
+	switch node.Labels["topology.kubernetes.io/numa-node"] {
+	case "1":
+		return 1
+	case "2":
+		return 2
+	case "3":
+		return 3
+	default:
+		return 0
+	}
+}
+
+// taintsBitmap packs a node's taints into a 32-bit mask keyed by taint key
+// (via FNV-32a, mod 32), so the feature vector stays a fixed size regardless
+// of how many taints a node carries, and two nodes with different taints
+// reliably produce different bitmaps (up to hash collisions) rather than
+// just differing by taint count.
+func taintsBitmap(node *corev1.Node) uint32 {
+	var bitmap uint32
+	for _, t := range node.Spec.Taints {
+		bitmap |= 1 << taintBit(t.Key)
+	}
+	return bitmap
+}
+
+func taintBit(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32() % 32
+}
+
+// affinityDigest summarizes a pod's affinity rules into a short, stable
+// string a policy network can embed instead of parsing the full
+// PodAffinity/NodeAffinity structures.
+func affinityDigest(pod *corev1.Pod) string {
+	if pod.Spec.Affinity == nil {
+		return ""
+	}
+	return fmt.Sprintf("na=%t/pa=%t/pa-anti=%t",
+		pod.Spec.Affinity.NodeAffinity != nil,
+		pod.Spec.Affinity.PodAffinity != nil,
+		pod.Spec.Affinity.PodAntiAffinity != nil)
+}
+
+// serveSchema starts a best-effort HTTP server exposing enc's schema at
+// /schema, so the Python trainer can self-configure instead of hard-coding
+// the feature layout. Errors are logged, not returned, since a failure to
+// bind the schema port shouldn't prevent the plugin from scheduling pods.
+func serveSchema(addr string, enc ObservationEncoder) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(enc.Schema()); err != nil {
+			log.Errorf("encoding observation schema: %v", err)
+		}
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Errorf("observation schema server on %s stopped: %v", addr, err)
+		}
+	}()
+}
@@ -0,0 +1,31 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func nodeWithTaints(keys ...string) *corev1.Node {
+	n := &corev1.Node{}
+	for _, k := range keys {
+		n.Spec.Taints = append(n.Spec.Taints, corev1.Taint{Key: k, Effect: corev1.TaintEffectNoSchedule})
+	}
+	return n
+}
+
+func TestTaintsBitmapDistinguishesKeys(t *testing.T) {
+	same := taintsBitmap(nodeWithTaints("gpu"))
+	other := taintsBitmap(nodeWithTaints("spot"))
+	if same == other {
+		t.Fatalf("expected different single taints to produce different bitmaps, got %d for both", same)
+	}
+}
+
+func TestTaintsBitmapStableForSameKey(t *testing.T) {
+	a := taintsBitmap(nodeWithTaints("gpu"))
+	b := taintsBitmap(nodeWithTaints("gpu"))
+	if a != b {
+		t.Fatalf("expected the same taint key to hash to the same bitmap, got %d and %d", a, b)
+	}
+}
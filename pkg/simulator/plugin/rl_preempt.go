@@ -0,0 +1,266 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/hkust-adsl/kubernetes-scheduler-simulator/pkg/simulator/feedback"
+)
+
+var _ framework.PostFilterPlugin = &RLSchedulerScorePlugin{}
+
+// preemptNode describes one candidate node's current occupants to the RL
+// service, so it can reason about which pods to evict rather than just which
+// node has the most free capacity.
+type preemptNode struct {
+	NodeName string       `json:"node_name"`
+	Features NodeFeatures `json:"features"`
+	PodNames []string     `json:"pod_names"`
+}
+
+// preemptRequest carries the Unschedulable pod and the cluster state to
+// /preempt.
+type preemptRequest struct {
+	Pod   PodFeatures   `json:"pod"`
+	Nodes []preemptNode `json:"nodes"`
+}
+
+// preemptCandidate is one (node, victims) option returned by the RL service,
+// most preferred first. VictimPodNames are "namespace/name".
+type preemptCandidate struct {
+	NodeName       string   `json:"node_name"`
+	VictimPodNames []string `json:"victim_pod_names"`
+}
+
+type preemptResponse struct {
+	Candidates []preemptCandidate `json:"candidates"`
+}
+
+// PostFilter runs when pod couldn't be scheduled against any node. It asks
+// the RL service for ranked preemption candidates, evicts the first
+// candidate whose victims pass the PDB/priority safety check, and nominates
+// that node so the next scheduling cycle can bind pod there once the
+// eviction has freed room.
+func (p *RLSchedulerScorePlugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	nodeInfos, err := p.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+
+	candidates, err := p.requestPreemption(ctx, pod, nodeInfos)
+	if err != nil {
+		return nil, framework.AsStatus(fmt.Errorf("requesting preemption candidates: %w", err))
+	}
+
+	byName := podsByNamespacedName(nodeInfos)
+	for _, c := range candidates {
+		victims, ok := p.safeVictims(pod, c.VictimPodNames, byName)
+		if !ok {
+			continue
+		}
+
+		if err := p.evictVictims(ctx, victims); err != nil {
+			log.Warnf("evicting victims for pod(%s/%s) on node %s: %v", pod.Namespace, pod.Name, c.NodeName, err)
+			continue
+		}
+
+		p.recordPreemption(ctx, pod, c.NodeName, victims)
+		return &framework.PostFilterResult{NominatedNodeName: c.NodeName}, framework.NewStatus(framework.Success)
+	}
+
+	return nil, framework.NewStatus(framework.Unschedulable, "RL service found no safe preemption candidates")
+}
+
+// requestPreemption POSTs the pod and candidate nodes (with their current
+// occupants) to RL_SCHEDULER_PREEMPT_ENDPOINT and returns the ranked
+// candidates it gets back.
+func (p *RLSchedulerScorePlugin) requestPreemption(ctx context.Context, pod *corev1.Pod, nodeInfos []*framework.NodeInfo) ([]preemptCandidate, error) {
+	req := preemptRequest{Pod: p.encoder.EncodePod(pod)}
+	for _, ni := range nodeInfos {
+		node := ni.Node()
+		if node == nil {
+			continue
+		}
+		podNames := make([]string, 0, len(ni.Pods))
+		for _, pi := range ni.Pods {
+			podNames = append(podNames, pi.Pod.Namespace+"/"+pi.Pod.Name)
+		}
+		req.Nodes = append(req.Nodes, preemptNode{
+			NodeName: node.Name,
+			Features: p.encoder.EncodeNode(pod, node),
+			PodNames: podNames,
+		})
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.preemptEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.preemptClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r preemptResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Candidates, nil
+}
+
+// safeVictims resolves victimNames to pods and rejects the whole candidate
+// if any victim isn't strictly lower priority than pod or is protected by a
+// PodDisruptionBudget with no disruptions left to give. PDB budgets are
+// tracked locally across the whole victim list (see pdbBudgetAllows) so two
+// victims covered by the same PDB can't each spend the same disruption.
+func (p *RLSchedulerScorePlugin) safeVictims(pod *corev1.Pod, victimNames []string, byName map[string]*corev1.Pod) ([]*corev1.Pod, bool) {
+	podPriority := int32(0)
+	if pod.Spec.Priority != nil {
+		podPriority = *pod.Spec.Priority
+	}
+
+	budgets := make(map[string]int32)
+	victims := make([]*corev1.Pod, 0, len(victimNames))
+	for _, name := range victimNames {
+		victim, ok := byName[name]
+		if !ok {
+			return nil, false
+		}
+
+		victimPriority := int32(0)
+		if victim.Spec.Priority != nil {
+			victimPriority = *victim.Spec.Priority
+		}
+		if victimPriority >= podPriority {
+			return nil, false
+		}
+
+		if !p.pdbBudgetAllows(victim, budgets) {
+			return nil, false
+		}
+
+		victims = append(victims, victim)
+	}
+	return victims, true
+}
+
+// pdbBudgetAllows reports whether evicting victim still fits within every
+// PodDisruptionBudget in its namespace that selects it, and tentatively
+// spends one disruption from each such PDB in budgets if so. budgets is
+// keyed by "namespace/name" and shared across an entire candidate's victim
+// list, seeded from a PDB's Status.DisruptionsAllowed on first use, so
+// multiple victims selected by the same PDB draw down one shared budget
+// instead of each being checked against the PDB's unchanged snapshot.
+func (p *RLSchedulerScorePlugin) pdbBudgetAllows(victim *corev1.Pod, budgets map[string]int32) bool {
+	informerFactory := p.handle.SharedInformerFactory()
+	if informerFactory == nil {
+		return true
+	}
+
+	pdbs, err := informerFactory.Policy().V1().PodDisruptionBudgets().Lister().PodDisruptionBudgets(victim.Namespace).List(labels.Everything())
+	if err != nil {
+		log.Warnf("listing PodDisruptionBudgets for %s/%s: %v", victim.Namespace, victim.Name, err)
+		return false
+	}
+
+	return reserveDisruptionBudget(victim, pdbs, budgets)
+}
+
+// reserveDisruptionBudget is the pure decision behind pdbBudgetAllows, split
+// out so the budget-batching logic can be table-tested without a
+// SharedInformerFactory.
+func reserveDisruptionBudget(victim *corev1.Pod, pdbs []*policyv1.PodDisruptionBudget, budgets map[string]int32) bool {
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(victim.Labels)) {
+			continue
+		}
+
+		key := pdb.Namespace + "/" + pdb.Name
+		remaining, seen := budgets[key]
+		if !seen {
+			remaining = pdb.Status.DisruptionsAllowed
+		}
+		if remaining <= 0 {
+			return false
+		}
+		budgets[key] = remaining - 1
+	}
+	return true
+}
+
+// evictVictims deletes each victim pod and recreates it as unbound Pending
+// so the simulator's scheduling queue picks it back up, mirroring how
+// deleting a victim in a real cluster lets its controller recreate it and
+// re-enter scheduling.
+func (p *RLSchedulerScorePlugin) evictVictims(ctx context.Context, victims []*corev1.Pod) error {
+	for _, v := range victims {
+		if err := p.handle.ClientSet().CoreV1().Pods(v.Namespace).Delete(ctx, v.Name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("deleting victim %s/%s: %w", v.Namespace, v.Name, err)
+		}
+
+		requeued := v.DeepCopy()
+		requeued.ResourceVersion = ""
+		requeued.Spec.NodeName = ""
+		requeued.Status.Phase = corev1.PodPending
+		if _, err := p.handle.ClientSet().CoreV1().Pods(v.Namespace).Create(ctx, requeued, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("re-enqueueing victim %s/%s: %w", v.Namespace, v.Name, err)
+		}
+	}
+	return nil
+}
+
+// recordPreemption ships the preemption decision to the feedback endpoint so
+// the policy can learn from preemption actions, not just placements.
+func (p *RLSchedulerScorePlugin) recordPreemption(ctx context.Context, pod *corev1.Pod, nodeName string, victims []*corev1.Pod) {
+	if !p.feedback.Enabled() {
+		return
+	}
+
+	victimNames := make([]string, 0, len(victims))
+	for _, v := range victims {
+		victimNames = append(victimNames, v.Namespace+"/"+v.Name)
+	}
+
+	action := fmt.Sprintf("%s:%s", nodeName, strings.Join(victimNames, ","))
+	stateJSON, err := json.Marshal(p.encoder.EncodePod(pod))
+	if err != nil {
+		log.Warnf("encoding preemption state for pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+		return
+	}
+
+	if err := p.feedback.RecordBind(ctx, stateJSON, stateJSON, feedback.ActionKindPreemption, action, nil, nil, 0, len(victims)); err != nil {
+		log.Warnf("recording preemption transition for pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// podsByNamespacedName indexes every pod currently on any node by
+// "namespace/name" for victim lookups.
+func podsByNamespacedName(nodeInfos []*framework.NodeInfo) map[string]*corev1.Pod {
+	out := make(map[string]*corev1.Pod)
+	for _, ni := range nodeInfos {
+		for _, pi := range ni.Pods {
+			out[pi.Pod.Namespace+"/"+pi.Pod.Name] = pi.Pod
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pdbWithBudget(name string, allowed int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "shared"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+	}
+}
+
+func victimPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{"app": "shared"}},
+	}
+}
+
+func TestReserveDisruptionBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed int32
+		victims []string
+		want    []bool // want[i] is the expected result of reserving victims[i]
+	}{
+		{
+			name:    "single victim within budget",
+			allowed: 1,
+			victims: []string{"v1"},
+			want:    []bool{true},
+		},
+		{
+			name:    "two victims share one budget, second is rejected",
+			allowed: 1,
+			victims: []string{"v1", "v2"},
+			want:    []bool{true, false},
+		},
+		{
+			name:    "budget covers every victim",
+			allowed: 2,
+			victims: []string{"v1", "v2"},
+			want:    []bool{true, true},
+		},
+		{
+			name:    "zero budget rejects immediately",
+			allowed: 0,
+			victims: []string{"v1"},
+			want:    []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdbs := []*policyv1.PodDisruptionBudget{pdbWithBudget("shared-pdb", tt.allowed)}
+			budgets := make(map[string]int32)
+
+			for i, name := range tt.victims {
+				got := reserveDisruptionBudget(victimPod(name), pdbs, budgets)
+				if got != tt.want[i] {
+					t.Errorf("reserveDisruptionBudget(%s) = %v, want %v", name, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReserveDisruptionBudgetIgnoresNonMatchingPDB(t *testing.T) {
+	pdb := pdbWithBudget("other-pdb", 0)
+	pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "unrelated"}}
+
+	budgets := make(map[string]int32)
+	if !reserveDisruptionBudget(victimPod("v1"), []*policyv1.PodDisruptionBudget{pdb}, budgets) {
+		t.Fatal("expected victim not selected by any PDB to be allowed")
+	}
+}
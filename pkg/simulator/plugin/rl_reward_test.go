@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func podWithRequests(cpu, mem, gpu string) *corev1.Pod {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(mem),
+	}
+	if gpu != "" {
+		requests[gpuResourceName] = resource.MustParse(gpu)
+	}
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: requests},
+			}},
+		},
+	}
+}
+
+func TestPodRequestTotals(t *testing.T) {
+	cpuMilli, memBytes, gpu := podRequestTotals(podWithRequests("2", "4Gi", "1"))
+	if cpuMilli != 2000 {
+		t.Errorf("cpuMilli = %d, want 2000", cpuMilli)
+	}
+	if memBytes != 4*1024*1024*1024 {
+		t.Errorf("memBytes = %d, want %d", memBytes, 4*1024*1024*1024)
+	}
+	if gpu != 1 {
+		t.Errorf("gpu = %d, want 1", gpu)
+	}
+}
+
+func TestPodRequestCostScalesWithGPU(t *testing.T) {
+	cpuOnly := podRequestCost(podWithRequests("2", "4Gi", ""))
+	withGPU := podRequestCost(podWithRequests("2", "4Gi", "1"))
+	if withGPU <= cpuOnly {
+		t.Fatalf("expected a GPU-requesting pod to cost more than an equivalent CPU-only pod: %v vs %v", withGPU, cpuOnly)
+	}
+}
+
+func TestFreeResourceFraction(t *testing.T) {
+	tests := []struct {
+		name                 string
+		allocatable, request int64
+		want                 float64
+	}{
+		{name: "half free", allocatable: 100, request: 50, want: 0.5},
+		{name: "fully packed", allocatable: 100, request: 100, want: 0},
+		{name: "over-requested clamps at zero", allocatable: 100, request: 150, want: 0},
+		{name: "zero allocatable", allocatable: 0, request: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freeResourceFraction(tt.allocatable, tt.request); got != tt.want {
+				t.Errorf("freeResourceFraction(%d, %d) = %v, want %v", tt.allocatable, tt.request, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,29 +1,35 @@
 package plugin
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	externalclientset "k8s.io/client-go/kubernetes"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
+	"github.com/hkust-adsl/kubernetes-scheduler-simulator/pkg/simulator/feedback"
 	simontype "github.com/hkust-adsl/kubernetes-scheduler-simulator/pkg/type"
 )
 
 // RLSchedulerScorePlugin queries an external RL service for node scores.
 type RLSchedulerScorePlugin struct {
-	handle   framework.Handle
-	client   *http.Client
-	endpoint string
+	handle          framework.Handle
+	scorer          Scorer
+	endpoint        string
+	encoder         ObservationEncoder
+	feedback        *feedback.Recorder
+	preemptEndpoint string
+	preemptClient   *http.Client
 }
 
 var _ framework.ScorePlugin = &RLSchedulerScorePlugin{}
@@ -32,9 +38,17 @@ var _ framework.BindPlugin = &RLSchedulerScorePlugin{}
 
 const rlScoreStateKey = "PreScore-RLSchedulerScorePlugin"
 
-// rlScoreState stores node scores returned by the RL service.
+// rlScoreState stores the RL service's node scores for this cycle, along
+// with the candidate nodes and pod that produced them so Bind can build a
+// feedback transition without re-querying the cluster.
 type rlScoreState struct {
+	pod    *corev1.Pod
+	nodes  []*corev1.Node
 	scores map[string]int64
+	// mask is aligned index-for-index with nodes, as computed by PreScore.
+	mask []bool
+	// validByName is mask indexed by node name, for fast lookup in Score.
+	validByName map[string]bool
 }
 
 // Clone implements the StateData interface.
@@ -46,10 +60,33 @@ func NewRLSchedulerScorePlugin(configuration runtime.Object, handle framework.Ha
 	if endpoint == "" {
 		endpoint = "http://localhost:5000/score"
 	}
+
+	encoder := NewDefaultObservationEncoder(nil)
+
+	scorer, err := NewScorer(encoder)
+	if err != nil {
+		return nil, fmt.Errorf("building RL scheduler scorer: %w", err)
+	}
+
+	if addr := os.Getenv("RL_SCHEDULER_SCHEMA_ADDR"); addr != "" {
+		serveSchema(addr, encoder)
+	}
+
+	recorder := feedback.NewRecorder(os.Getenv("RL_SCHEDULER_FEEDBACK_ENDPOINT"), feedback.DefaultRewardConfig())
+
+	preemptEndpoint := os.Getenv("RL_SCHEDULER_PREEMPT_ENDPOINT")
+	if preemptEndpoint == "" {
+		preemptEndpoint = strings.TrimSuffix(endpoint, "/score") + "/preempt"
+	}
+
 	return &RLSchedulerScorePlugin{
-		handle:   handle,
-		client:   &http.Client{},
-		endpoint: endpoint,
+		handle:          handle,
+		scorer:          scorer,
+		endpoint:        endpoint,
+		encoder:         encoder,
+		feedback:        recorder,
+		preemptEndpoint: preemptEndpoint,
+		preemptClient:   &http.Client{},
 	}, nil
 }
 
@@ -58,47 +95,61 @@ func (p *RLSchedulerScorePlugin) Name() string {
 	return simontype.RLSchedulerScorePluginName
 }
 
-// rlRequest carries the scheduling context sent to the RL service.
+// rlRequest carries the scheduling context sent to the RL service, encoded
+// as the compact feature vectors produced by an ObservationEncoder rather
+// than raw corev1.Pod/corev1.Node objects.
 type rlRequest struct {
-	Pod   *corev1.Pod   `json:"pod"`
-	Nodes []corev1.Node `json:"nodes"`
+	Pod       PodFeatures    `json:"pod"`
+	Nodes     []NodeFeatures `json:"nodes"`
+	NodeNames []string       `json:"node_names"`
+	// ValidMask is aligned index-for-index with Nodes/NodeNames; false
+	// marks a node the pod cannot be placed on.
+	ValidMask []bool `json:"valid_mask,omitempty"`
 }
 
-// rlResponse captures scores from the RL service.
+// rlResponse captures scores from the RL service, keyed by node name, plus
+// the pre-masked-softmax logits (same node order as the request) so
+// off-policy training has access to the full policy output, not just the
+// scores the simulator acts on.
 type rlResponse struct {
 	Scores map[string]int64 `json:"scores"`
+	Logits []float32        `json:"logits,omitempty"`
 }
 
-// PreScore calls the RL service once per scheduling cycle to obtain node scores.
-func (p *RLSchedulerScorePlugin) PreScore(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodes []*corev1.Node) *framework.Status {
-	req := rlRequest{Pod: pod.DeepCopy()}
-	for _, n := range nodes {
-		req.Nodes = append(req.Nodes, *n)
+// encodeRequest builds the wire payload for one PreScore call using enc. A
+// nil mask means "not applicable" (e.g. feedback state encoding) and is
+// omitted from the payload.
+func encodeRequest(enc ObservationEncoder, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) rlRequest {
+	req := rlRequest{
+		Pod:       enc.EncodePod(pod),
+		Nodes:     make([]NodeFeatures, 0, len(nodes)),
+		NodeNames: make([]string, 0, len(nodes)),
+		ValidMask: mask,
 	}
-
-	payload, err := json.Marshal(req)
-	if err != nil {
-		return framework.AsStatus(err)
+	for _, n := range nodes {
+		req.Nodes = append(req.Nodes, enc.EncodeNode(pod, n))
+		req.NodeNames = append(req.NodeNames, n.Name)
 	}
+	return req
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
-	if err != nil {
-		return framework.AsStatus(err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
+// PreScore calls the RL service once per scheduling cycle to obtain node
+// scores. The underlying Transport decides whether that means a fresh HTTP
+// POST or a request multiplexed onto a long-lived gRPC stream.
+func (p *RLSchedulerScorePlugin) PreScore(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodes []*corev1.Node) *framework.Status {
+	mask := computeValidMask(pod, nodes, p.requestedByNode(nodes))
 
-	resp, err := p.client.Do(httpReq)
+	scores, err := p.scorer.Score(ctx, pod, nodes, mask)
 	if err != nil {
 		return framework.AsStatus(err)
 	}
-	defer resp.Body.Close()
 
-	var r rlResponse
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return framework.AsStatus(err)
+	validByName := make(map[string]bool, len(nodes))
+	for i, n := range nodes {
+		validByName[n.Name] = mask[i]
 	}
 
-	state.Write(rlScoreStateKey, &rlScoreState{scores: r.Scores})
+	state.Write(rlScoreStateKey, &rlScoreState{pod: pod, nodes: nodes, scores: scores, mask: mask, validByName: validByName})
 	return nil
 }
 
@@ -112,6 +163,10 @@ func (p *RLSchedulerScorePlugin) Score(ctx context.Context, state *framework.Cyc
 	if !ok {
 		return 0, framework.AsStatus(fmt.Errorf("cannot convert saved state to rlScoreState"))
 	}
+
+	if valid, ok := s.validByName[nodeName]; ok && !valid {
+		return framework.MinNodeScore, framework.NewStatus(framework.Skip, fmt.Sprintf("node %s excluded by action mask", nodeName))
+	}
 	if score, ok := s.scores[nodeName]; ok {
 		return score, framework.NewStatus(framework.Success)
 	}
@@ -121,7 +176,6 @@ func (p *RLSchedulerScorePlugin) Score(ctx context.Context, state *framework.Cyc
 // ScoreExtensions is not used.
 func (p *RLSchedulerScorePlugin) ScoreExtensions() framework.ScoreExtensions { return nil }
 
-
 // Bind binds the pod to the given node. It mirrors the default binding
 // behaviour used by other simulator plugins.
 func (p *RLSchedulerScorePlugin) Bind(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string) *framework.Status {
@@ -152,5 +206,198 @@ func (p *RLSchedulerScorePlugin) Bind(ctx context.Context, state *framework.Cycl
 	default:
 		return framework.NewStatus(framework.Error, fmt.Sprintf("Unknown client type: %T", t))
 	}
+
+	p.recordExperience(ctx, state, pod, nodeName)
 	return nil
 }
+
+// recordExperience builds a (state, action, reward, next_state) transition
+// for this Bind call and ships it to the feedback endpoint. It is best
+// effort: a feedback-service hiccup must never fail a bind that already
+// succeeded against the cluster.
+func (p *RLSchedulerScorePlugin) recordExperience(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string) {
+	if !p.feedback.Enabled() {
+		return
+	}
+
+	c, err := state.Read(rlScoreStateKey)
+	if err != nil {
+		log.Warnf("no PreScore state for pod(%s/%s), skipping feedback record: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	s, ok := c.(*rlScoreState)
+	if !ok {
+		return
+	}
+
+	before := s.nodes
+	after := make([]*corev1.Node, len(before))
+	for i, n := range before {
+		if n.Name != nodeName {
+			after[i] = n
+			continue
+		}
+		placed := n.DeepCopy()
+		subtractPodRequests(placed, pod)
+		after[i] = placed
+	}
+
+	// The mask travels with the state, not just the wire request, so
+	// off-policy training can re-derive which actions were even available
+	// when this transition was recorded.
+	stateJSON, err := json.Marshal(encodeRequest(p.encoder, s.pod, before, s.mask))
+	if err != nil {
+		log.Warnf("encoding feedback state for pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	nextStateJSON, err := json.Marshal(encodeRequest(p.encoder, s.pod, after, s.mask))
+	if err != nil {
+		log.Warnf("encoding feedback next_state for pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+		return
+	}
+
+	// Unlike the state/next_state feature vectors above, the reward needs
+	// each node's *actual* free capacity, not its static Allocatable: a
+	// node already packed with pods and one sitting idle both report the
+	// same Allocatable. occBefore/occAfter come from the scheduler's live
+	// NodeInfo snapshot, which tracks bound-pod requests (including GPU).
+	occBefore, occAfter := p.clusterOccupancy(s.nodes, pod, nodeName)
+	podCost := podRequestCost(pod)
+
+	if err := p.feedback.RecordBind(ctx, stateJSON, nextStateJSON, feedback.ActionKindPlacement, nodeName, occBefore, occAfter, podCost, 0); err != nil {
+		log.Warnf("recording feedback transition for pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// subtractPodRequests reduces n's allocatable CPU/memory/GPU by pod's
+// requests, approximating the node's post-bind state for the next_state
+// feature vector sent to the feedback endpoint.
+func subtractPodRequests(n *corev1.Node, pod *corev1.Pod) {
+	var cpu, mem, gpu resource.Quantity
+	for _, c := range pod.Spec.Containers {
+		cpu.Add(*c.Resources.Requests.Cpu())
+		mem.Add(*c.Resources.Requests.Memory())
+		if q, ok := c.Resources.Requests[gpuResourceName]; ok {
+			gpu.Add(q)
+		}
+	}
+
+	remainingCPU := n.Status.Allocatable.Cpu().DeepCopy()
+	remainingCPU.Sub(cpu)
+	remainingMem := n.Status.Allocatable.Memory().DeepCopy()
+	remainingMem.Sub(mem)
+	n.Status.Allocatable[corev1.ResourceCPU] = remainingCPU
+	n.Status.Allocatable[corev1.ResourceMemory] = remainingMem
+
+	if existingGPU, ok := n.Status.Allocatable[gpuResourceName]; ok {
+		remainingGPU := existingGPU.DeepCopy()
+		remainingGPU.Sub(gpu)
+		n.Status.Allocatable[gpuResourceName] = remainingGPU
+	}
+}
+
+// gpuResourceName is the well-known extended resource name used throughout
+// the plugin to request/report GPUs.
+const gpuResourceName = corev1.ResourceName("nvidia.com/gpu")
+
+// requestedByNode looks up each node's currently-bound-pod resource
+// requests from the scheduler's live NodeInfo snapshot, keyed by node name,
+// for computeValidMask to check actual free capacity against rather than a
+// node's static Allocatable. A node missing from the snapshot is simply
+// omitted, so computeValidMask falls back to treating it as unoccupied.
+func (p *RLSchedulerScorePlugin) requestedByNode(nodes []*corev1.Node) map[string]*framework.Resource {
+	snapshot := p.handle.SnapshotSharedLister()
+	out := make(map[string]*framework.Resource, len(nodes))
+	for _, n := range nodes {
+		ni, err := snapshot.NodeInfos().Get(n.Name)
+		if err != nil {
+			log.Warnf("looking up NodeInfo for %s: %v", n.Name, err)
+			continue
+		}
+		out[n.Name] = ni.Requested
+	}
+	return out
+}
+
+// clusterOccupancy builds the real per-node free-capacity fractions (CPU,
+// memory, GPU) for nodes both before and after pod is bound to placedNode,
+// reading bound-pod occupancy from the scheduler's NodeInfo snapshot rather
+// than a node's static Allocatable/Capacity. A node missing from the
+// snapshot (e.g. removed between PreScore and Bind) contributes a
+// zero-value occupancy rather than failing the whole transition.
+func (p *RLSchedulerScorePlugin) clusterOccupancy(nodes []*corev1.Node, pod *corev1.Pod, placedNode string) (before, after []feedback.NodeOccupancy) {
+	snapshot := p.handle.SnapshotSharedLister()
+	podCPU, podMem, podGPU := podRequestTotals(pod)
+
+	before = make([]feedback.NodeOccupancy, len(nodes))
+	after = make([]feedback.NodeOccupancy, len(nodes))
+	for i, n := range nodes {
+		ni, err := snapshot.NodeInfos().Get(n.Name)
+		if err != nil {
+			log.Warnf("looking up NodeInfo for %s: %v", n.Name, err)
+			continue
+		}
+
+		allocCPU, allocMem := ni.Allocatable.MilliCPU, ni.Allocatable.Memory
+		allocGPU := ni.Allocatable.ScalarResources[gpuResourceName]
+		reqCPU, reqMem := ni.Requested.MilliCPU, ni.Requested.Memory
+		reqGPU := ni.Requested.ScalarResources[gpuResourceName]
+
+		before[i] = feedback.NodeOccupancy{
+			FreeCPUFraction:    freeResourceFraction(allocCPU, reqCPU),
+			FreeMemoryFraction: freeResourceFraction(allocMem, reqMem),
+			FreeGPUFraction:    freeResourceFraction(allocGPU, reqGPU),
+		}
+
+		if n.Name == placedNode {
+			reqCPU += podCPU
+			reqMem += podMem
+			reqGPU += podGPU
+		}
+		after[i] = feedback.NodeOccupancy{
+			FreeCPUFraction:    freeResourceFraction(allocCPU, reqCPU),
+			FreeMemoryFraction: freeResourceFraction(allocMem, reqMem),
+			FreeGPUFraction:    freeResourceFraction(allocGPU, reqGPU),
+		}
+	}
+	return before, after
+}
+
+// freeResourceFraction is the fraction of allocatable left free once
+// requested is accounted for, clamped to [0, 1].
+func freeResourceFraction(allocatable, requested int64) float64 {
+	if allocatable <= 0 {
+		return 0
+	}
+	free := allocatable - requested
+	if free < 0 {
+		free = 0
+	}
+	return float64(free) / float64(allocatable)
+}
+
+// podRequestTotals sums pod's per-container resource requests into the same
+// units NodeInfo.Requested uses, so they can be added to a node's requested
+// occupancy directly.
+func podRequestTotals(pod *corev1.Pod) (cpuMilli, memBytes, gpu int64) {
+	for _, c := range pod.Spec.Containers {
+		cpuMilli += c.Resources.Requests.Cpu().MilliValue()
+		memBytes += c.Resources.Requests.Memory().Value()
+		if q, ok := c.Resources.Requests[gpuResourceName]; ok {
+			gpu += q.Value()
+		}
+	}
+	return cpuMilli, memBytes, gpu
+}
+
+// podRequestCost approximates the runtime cost MakespanWeight penalizes:
+// larger and GPU-bound pods take longer to run, so they should cost more
+// than a flat per-placement constant.
+func podRequestCost(pod *corev1.Pod) float64 {
+	var cpu, gpu float64
+	for _, c := range pod.Spec.Containers {
+		cpu += c.Resources.Requests.Cpu().AsApproximateFloat64()
+		gpu += gpuShare(c.Resources.Requests)
+	}
+	return cpu + gpu
+}
@@ -0,0 +1,320 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	ort "github.com/yalue/onnxruntime_go"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Scorer is the narrow interface RLSchedulerScorePlugin needs from whatever
+// is producing node scores: a remote RL service over HTTP/gRPC (Transport
+// already satisfies this), or a model loaded in-process. Keeping it separate
+// from Transport lets a LocalModelScorer and a circuit breaker compose with
+// remote transports without either side knowing about the other.
+type Scorer interface {
+	Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error)
+	Close() error
+}
+
+// defaultCircuitBreakerThreshold is how many consecutive remote failures
+// (including timeouts) trip the breaker over to the local model, if one is
+// configured.
+const defaultCircuitBreakerThreshold = 5
+
+// defaultCircuitBreakerProbeInterval is how many Score calls the breaker
+// serves from the local model, once tripped, before letting one call probe
+// remote again.
+const defaultCircuitBreakerProbeInterval = 20
+
+// NewScorer builds the Scorer RLSchedulerScorePlugin uses. It always builds
+// the remote transport selected by RL_SCHEDULER_TRANSPORT; if
+// RL_SCHEDULER_MODEL_PATH is also set, remote calls are wrapped in a circuit
+// breaker that falls back to the local model after repeated failures.
+func NewScorer(enc ObservationEncoder) (Scorer, error) {
+	kind := TransportKind(os.Getenv("RL_SCHEDULER_TRANSPORT"))
+	remote, err := NewTransport(kind, rlTransportEndpoint(kind), enc)
+	if err != nil {
+		return nil, fmt.Errorf("building remote RL transport: %w", err)
+	}
+
+	modelPath := os.Getenv("RL_SCHEDULER_MODEL_PATH")
+	if modelPath == "" {
+		return remote, nil
+	}
+
+	local, err := NewLocalModelScorer(modelPath, enc)
+	if err != nil {
+		return nil, fmt.Errorf("loading local RL model from %s: %w", modelPath, err)
+	}
+
+	threshold := defaultCircuitBreakerThreshold
+	if v := os.Getenv("RL_SCHEDULER_CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			threshold = n
+		}
+	}
+
+	probeInterval := defaultCircuitBreakerProbeInterval
+	if v := os.Getenv("RL_SCHEDULER_CIRCUIT_BREAKER_PROBE_INTERVAL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			probeInterval = n
+		}
+	}
+
+	return newCircuitBreakerScorer(remote, local, threshold, probeInterval), nil
+}
+
+// rlTransportEndpoint resolves the address NewTransport dials for kind.
+// grpc.NewClient (used by the gRPC transport) dials a bare host:port, not a
+// URL, so gRPC gets its own RL_SCHEDULER_GRPC_ENDPOINT variable and default
+// instead of silently reusing RL_SCHEDULER_ENDPOINT's
+// "http://host:port/path" value, which it can't parse.
+func rlTransportEndpoint(kind TransportKind) string {
+	if kind == TransportGRPC {
+		if v := os.Getenv("RL_SCHEDULER_GRPC_ENDPOINT"); v != "" {
+			return v
+		}
+		return "localhost:5001"
+	}
+	if v := os.Getenv("RL_SCHEDULER_ENDPOINT"); v != "" {
+		return v
+	}
+	return "http://localhost:5000/score"
+}
+
+// LocalModelScorer runs an ONNX model in-process using the same
+// ObservationEncoder features the remote transports send over the wire, so
+// simulation runs that call Score millions of times don't pay an RPC hop and
+// deployments can run without the RL sidecar at all.
+//
+// The model is expected to take a [num_nodes, num_node_features] float32
+// tensor of node features and return a [num_nodes] float32 tensor of scores
+// in the same node order. Sessions are cached by node count (see
+// cachedSession) rather than built per Score call, since session
+// construction (model load + graph init) is what actually dominates the
+// millions of calls a simulation run makes, not the per-cycle inference.
+type LocalModelScorer struct {
+	modelPath string
+	encoder   ObservationEncoder
+
+	mu       sync.Mutex
+	sessions map[int]*cachedSession
+}
+
+// cachedSession pairs an ONNX session with the input/output tensors it was
+// built against, so repeat Score calls at the same node count can overwrite
+// the input tensor's data in place and Run again instead of reallocating.
+type cachedSession struct {
+	session *ort.AdvancedSession
+	input   *ort.Tensor[float32]
+	output  *ort.Tensor[float32]
+}
+
+// NewLocalModelScorer loads the ONNX runtime and validates that modelPath is
+// readable. Sessions are built lazily per node count on first use, since the
+// input shape depends on the number of candidate nodes at Score time.
+func NewLocalModelScorer(modelPath string, enc ObservationEncoder) (*LocalModelScorer, error) {
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("reading model file: %w", err)
+	}
+	if err := ort.InitializeEnvironment(); err != nil {
+		return nil, fmt.Errorf("initializing onnxruntime: %w", err)
+	}
+	return &LocalModelScorer{modelPath: modelPath, encoder: enc, sessions: make(map[int]*cachedSession)}, nil
+}
+
+// Score flattens the encoded node features into the cached session's input
+// tensor for this node count, runs the model, and maps the output scores
+// back onto node names.
+func (l *LocalModelScorer) Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error) {
+	if len(nodes) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	featureDim := len(flattenNodeFeatures(l.encoder.EncodeNode(pod, nodes[0])))
+	input := make([]float32, 0, len(nodes)*featureDim)
+	for _, n := range nodes {
+		input = append(input, flattenNodeFeatures(l.encoder.EncodeNode(pod, n))...)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sess, err := l.sessionForLocked(len(nodes), featureDim)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(sess.input.GetData(), input)
+	if err := sess.session.Run(); err != nil {
+		return nil, fmt.Errorf("running onnx session: %w", err)
+	}
+
+	out := sess.output.GetData()
+	scores := make(map[string]int64, len(nodes))
+	for i, n := range nodes {
+		if i < len(mask) && !mask[i] {
+			continue
+		}
+		scores[n.Name] = int64(out[i])
+	}
+	return scores, nil
+}
+
+// sessionForLocked returns the session cached for numNodes candidate nodes,
+// building and caching one on first use. l.mu must be held by the caller.
+func (l *LocalModelScorer) sessionForLocked(numNodes, featureDim int) (*cachedSession, error) {
+	if sess, ok := l.sessions[numNodes]; ok {
+		return sess, nil
+	}
+
+	inputTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(int64(numNodes), int64(featureDim)))
+	if err != nil {
+		return nil, fmt.Errorf("building input tensor: %w", err)
+	}
+
+	outputTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(int64(numNodes)))
+	if err != nil {
+		inputTensor.Destroy()
+		return nil, fmt.Errorf("building output tensor: %w", err)
+	}
+
+	session, err := ort.NewAdvancedSession(l.modelPath,
+		[]string{"node_features"}, []string{"scores"},
+		[]ort.Value{inputTensor}, []ort.Value{outputTensor}, nil)
+	if err != nil {
+		inputTensor.Destroy()
+		outputTensor.Destroy()
+		return nil, fmt.Errorf("creating onnx session: %w", err)
+	}
+
+	sess := &cachedSession{session: session, input: inputTensor, output: outputTensor}
+	l.sessions[numNodes] = sess
+	return sess, nil
+}
+
+// flattenNodeFeatures turns a NodeFeatures struct into the flat float32
+// vector the ONNX model's input tensor expects.
+func flattenNodeFeatures(f NodeFeatures) []float32 {
+	out := []float32{
+		float32(f.FreeCPUFraction),
+		float32(f.FreeMemoryFraction),
+		float32(f.FreeGPUFraction),
+		float32(f.NUMAIndex),
+		float32(f.TaintsBitmap),
+	}
+	for _, v := range f.GPUModelOneHot {
+		out = append(out, float32(v))
+	}
+	return out
+}
+
+// Close destroys every cached session and its tensors, then releases the
+// ONNX runtime environment.
+func (l *LocalModelScorer) Close() error {
+	l.mu.Lock()
+	for _, sess := range l.sessions {
+		sess.session.Destroy()
+		sess.input.Destroy()
+		sess.output.Destroy()
+	}
+	l.sessions = nil
+	l.mu.Unlock()
+
+	return ort.DestroyEnvironment()
+}
+
+// circuitBreakerScorer scores via remote until it sees threshold consecutive
+// failures, then trips over to local (when one is configured). Once
+// tripped, it still lets one call every probeInterval calls try remote
+// again (half-open), so a recovered RL service is rediscovered instead of
+// being latched to local forever.
+type circuitBreakerScorer struct {
+	remote        Scorer
+	local         Scorer
+	threshold     int
+	probeInterval int
+
+	mu              sync.Mutex
+	consecutiveFail int
+	tripped         bool
+	callsSinceTrip  int
+}
+
+func newCircuitBreakerScorer(remote, local Scorer, threshold, probeInterval int) *circuitBreakerScorer {
+	return &circuitBreakerScorer{remote: remote, local: local, threshold: threshold, probeInterval: probeInterval}
+}
+
+func (b *circuitBreakerScorer) Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error) {
+	if b.isTripped() && !b.shouldProbe() {
+		return b.local.Score(ctx, pod, nodes, mask)
+	}
+
+	scores, err := b.remote.Score(ctx, pod, nodes, mask)
+	if err == nil {
+		b.recordSuccess()
+		return scores, nil
+	}
+
+	tripped := b.recordFailure()
+	if tripped {
+		log.Warnf("RL remote scorer failed %d times in a row, falling back to local model: %v", b.threshold, err)
+	}
+	if tripped || b.isTripped() {
+		return b.local.Score(ctx, pod, nodes, mask)
+	}
+	return nil, err
+}
+
+func (b *circuitBreakerScorer) isTripped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tripped
+}
+
+// shouldProbe reports whether a tripped breaker should let this call through
+// to remote rather than going straight to local, advancing the half-open
+// countdown as a side effect.
+func (b *circuitBreakerScorer) shouldProbe() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.callsSinceTrip++
+	if b.callsSinceTrip >= b.probeInterval {
+		b.callsSinceTrip = 0
+		return true
+	}
+	return false
+}
+
+func (b *circuitBreakerScorer) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFail = 0
+	b.callsSinceTrip = 0
+	b.tripped = false
+}
+
+// recordFailure returns true the call that crosses the threshold.
+func (b *circuitBreakerScorer) recordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFail++
+	if b.consecutiveFail >= b.threshold && !b.tripped {
+		b.tripped = true
+		return true
+	}
+	return false
+}
+
+func (b *circuitBreakerScorer) Close() error {
+	if err := b.remote.Close(); err != nil {
+		return err
+	}
+	return b.local.Close()
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// fakeScorer is a Scorer double that can be told to fail for a number of
+// calls, for exercising circuitBreakerScorer without a remote or ONNX.
+type fakeScorer struct {
+	result    map[string]int64
+	failCount int
+	calls     int
+}
+
+func (f *fakeScorer) Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error) {
+	f.calls++
+	if f.failCount > 0 {
+		f.failCount--
+		return nil, errors.New("boom")
+	}
+	return f.result, nil
+}
+
+func (f *fakeScorer) Close() error { return nil }
+
+func TestCircuitBreakerScorerTripsAfterThreshold(t *testing.T) {
+	remote := &fakeScorer{failCount: 10}
+	local := &fakeScorer{result: map[string]int64{"local": 1}}
+	b := newCircuitBreakerScorer(remote, local, 3, 100)
+
+	for i := 0; i < 3; i++ {
+		scores, err := b.Score(context.Background(), nil, nil, nil)
+		if i < 2 {
+			if err == nil {
+				t.Fatalf("call %d: expected remote failure to surface before the breaker trips", i)
+			}
+			continue
+		}
+		// The failure that crosses the threshold trips the breaker and is
+		// served from local in the same call.
+		if err != nil {
+			t.Fatalf("call %d: expected the tripping call to fall back to local, got %v", i, err)
+		}
+		if scores["local"] != 1 {
+			t.Fatalf("call %d: expected the tripping call to return local's scores, got %v", i, scores)
+		}
+	}
+
+	if !b.isTripped() {
+		t.Fatal("expected breaker to be tripped after 3 consecutive failures")
+	}
+
+	scores, err := b.Score(context.Background(), nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected tripped breaker to serve from local without error, got %v", err)
+	}
+	if scores["local"] != 1 {
+		t.Fatalf("expected tripped breaker to return local's scores, got %v", scores)
+	}
+}
+
+func TestCircuitBreakerScorerProbesAndRecovers(t *testing.T) {
+	remote := &fakeScorer{failCount: 2, result: map[string]int64{"remote": 1}}
+	local := &fakeScorer{result: map[string]int64{"local": 1}}
+	b := newCircuitBreakerScorer(remote, local, 2, 1)
+
+	if _, err := b.Score(context.Background(), nil, nil, nil); err == nil {
+		t.Fatal("call 0: expected failure")
+	}
+	if scores, err := b.Score(context.Background(), nil, nil, nil); err != nil || scores["local"] != 1 {
+		t.Fatalf("call 1: expected the tripping call to fall back to local, got %v, %v", scores, err)
+	}
+	if !b.isTripped() {
+		t.Fatal("expected breaker to be tripped")
+	}
+
+	scores, err := b.Score(context.Background(), nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected the probe call to succeed against remote, got error: %v", err)
+	}
+	if scores["remote"] != 1 {
+		t.Fatalf("expected the probe to use remote's result, got %v", scores)
+	}
+	if b.isTripped() {
+		t.Fatal("expected a successful probe to reset the breaker")
+	}
+}
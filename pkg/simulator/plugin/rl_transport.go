@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hkust-adsl/kubernetes-scheduler-simulator/pkg/simulator/plugin/rlpb"
+)
+
+// TransportKind selects how the plugin talks to the RL service.
+type TransportKind string
+
+const (
+	// TransportHTTP issues one POST per scheduling cycle.
+	TransportHTTP TransportKind = "http"
+	// TransportGRPC keeps a single bidirectional stream open across
+	// cycles so the RL service can batch pods before a forward pass.
+	TransportGRPC TransportKind = "grpc"
+)
+
+// Transport abstracts how PreScore requests reach the RL service and how
+// their responses come back, so the plugin can swap HTTP for a streaming
+// gRPC connection without changing its scoring logic.
+type Transport interface {
+	// Score sends the pod/node observations for one scheduling cycle,
+	// along with the per-node validity mask computed by PreScore, and
+	// returns the per-node scores reported by the RL service. mask is
+	// aligned index-for-index with nodes.
+	Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error)
+	// Close releases any connection held by the transport.
+	Close() error
+}
+
+// NewTransport builds the Transport selected by kind against endpoint. enc
+// encodes the raw Pod/Node objects into the compact feature vectors that
+// actually go over the wire.
+func NewTransport(kind TransportKind, endpoint string, enc ObservationEncoder) (Transport, error) {
+	switch kind {
+	case TransportGRPC:
+		return newGRPCTransport(endpoint, enc)
+	case TransportHTTP, "":
+		return &httpTransport{client: &http.Client{}, endpoint: endpoint, encoder: enc}, nil
+	default:
+		return nil, fmt.Errorf("unknown RL_SCHEDULER_TRANSPORT %q", kind)
+	}
+}
+
+// httpTransport is the original one-shot HTTP POST path.
+type httpTransport struct {
+	client   *http.Client
+	endpoint string
+	encoder  ObservationEncoder
+}
+
+func (t *httpTransport) Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error) {
+	req := encodeRequest(t.encoder, pod, nodes, mask)
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r rlResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Scores, nil
+}
+
+func (t *httpTransport) Close() error { return nil }
+
+// grpcTransport keeps a single RLScorer/ScoreStream open for the lifetime of
+// the plugin. Requests are tagged with a cycle ID so responses, which may
+// arrive batched and out of order relative to requests, can be matched back
+// to the PreScore call waiting on them.
+type grpcTransport struct {
+	conn    *grpc.ClientConn
+	stream  rlpb.RLScorer_ScoreStreamClient
+	encoder ObservationEncoder
+
+	mu       sync.Mutex
+	waiters  map[string]chan *rlpb.RLResponse
+	closed   bool
+	closeErr error
+}
+
+func newGRPCTransport(endpoint string, enc ObservationEncoder) (*grpcTransport, error) {
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dialing RL service at %s: %w", endpoint, err)
+	}
+
+	stream, err := rlpb.NewRLScorerClient(conn).ScoreStream(context.Background())
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening RL score stream: %w", err)
+	}
+
+	t := &grpcTransport{
+		conn:    conn,
+		stream:  stream,
+		encoder: enc,
+		waiters: make(map[string]chan *rlpb.RLResponse),
+	}
+	go t.recvLoop()
+	return t, nil
+}
+
+func (t *grpcTransport) recvLoop() {
+	for {
+		resp, err := t.stream.Recv()
+		if err != nil {
+			log.Errorf("RL score stream closed: %v", err)
+			t.mu.Lock()
+			t.closed = true
+			t.closeErr = err
+			for _, ch := range t.waiters {
+				close(ch)
+			}
+			t.waiters = nil
+			t.mu.Unlock()
+			return
+		}
+
+		t.mu.Lock()
+		ch, ok := t.waiters[resp.CycleId]
+		if ok {
+			delete(t.waiters, resp.CycleId)
+		}
+		t.mu.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+func (t *grpcTransport) Score(ctx context.Context, pod *corev1.Pod, nodes []*corev1.Node, mask []bool) (map[string]int64, error) {
+	req := encodeRequest(t.encoder, pod, nodes, mask)
+
+	podJSON, err := json.Marshal(req.Pod)
+	if err != nil {
+		return nil, err
+	}
+	nodesJSON := make([][]byte, 0, len(req.Nodes))
+	for _, n := range req.Nodes {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return nil, err
+		}
+		nodesJSON = append(nodesJSON, b)
+	}
+
+	cycleID := uuid.NewString()
+	ch := make(chan *rlpb.RLResponse, 1)
+
+	t.mu.Lock()
+	if t.closed {
+		closeErr := t.closeErr
+		t.mu.Unlock()
+		return nil, fmt.Errorf("RL score stream is closed: %w", closeErr)
+	}
+	t.waiters[cycleID] = ch
+	t.mu.Unlock()
+
+	if err := t.stream.Send(&rlpb.RLRequest{CycleId: cycleID, Pod: podJSON, Nodes: nodesJSON, ValidMask: req.ValidMask}); err != nil {
+		t.mu.Lock()
+		delete(t.waiters, cycleID)
+		t.mu.Unlock()
+		return nil, fmt.Errorf("sending PreScore request: %w", err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("RL score stream closed before cycle %s was answered", cycleID)
+		}
+		return resp.Scores, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.waiters, cycleID)
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *grpcTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hkust-adsl/kubernetes-scheduler-simulator/pkg/simulator/plugin/rlpb"
+)
+
+// fakeScoreStream is a minimal rlpb.RLScorer_ScoreStreamClient test double.
+// With recvCh set, Send echoes back a response carrying the request's cycle
+// ID so Score's wait resolves; with recvCh nil, Recv fails immediately with
+// recvErr, simulating a stream that's already broken.
+type fakeScoreStream struct {
+	grpc.ClientStream
+	recvCh  chan *rlpb.RLResponse
+	recvErr error
+}
+
+func (f *fakeScoreStream) Send(m *rlpb.RLRequest) error {
+	if f.recvCh != nil {
+		go func(cycleID string) {
+			f.recvCh <- &rlpb.RLResponse{CycleId: cycleID, Scores: map[string]int64{"node-a": 42}}
+		}(m.CycleId)
+	}
+	return nil
+}
+
+func (f *fakeScoreStream) Recv() (*rlpb.RLResponse, error) {
+	if f.recvCh == nil {
+		return nil, f.recvErr
+	}
+	resp, ok := <-f.recvCh
+	if !ok {
+		return nil, f.recvErr
+	}
+	return resp, nil
+}
+
+func newTestGRPCTransport(stream rlpb.RLScorer_ScoreStreamClient) *grpcTransport {
+	return &grpcTransport{
+		stream:  stream,
+		encoder: NewDefaultObservationEncoder(nil),
+		waiters: make(map[string]chan *rlpb.RLResponse),
+	}
+}
+
+func TestGRPCTransportScoreRoundTrip(t *testing.T) {
+	fake := &fakeScoreStream{recvCh: make(chan *rlpb.RLResponse)}
+	transport := newTestGRPCTransport(fake)
+	go transport.recvLoop()
+
+	pod := testPod("1", "1Gi", nil, nil)
+	node := testNode("2", "2Gi", nil, nil)
+
+	scores, err := transport.Score(context.Background(), pod, []*corev1.Node{node}, []bool{true})
+	if err != nil {
+		t.Fatalf("Score returned an error: %v", err)
+	}
+	if scores["node-a"] != 42 {
+		t.Errorf("scores[node-a] = %d, want 42", scores["node-a"])
+	}
+}
+
+// TestGRPCTransportScoreAfterStreamClosed guards against a regression where
+// recvLoop nil-ed out the waiters map on a broken stream, and Score's
+// unconditional write to that map panicked on the next PreScore call instead
+// of returning an error.
+func TestGRPCTransportScoreAfterStreamClosed(t *testing.T) {
+	fake := &fakeScoreStream{recvErr: errors.New("stream broken")}
+	transport := newTestGRPCTransport(fake)
+
+	done := make(chan struct{})
+	go func() {
+		transport.recvLoop()
+		close(done)
+	}()
+	<-done
+
+	pod := testPod("1", "1Gi", nil, nil)
+	node := testNode("2", "2Gi", nil, nil)
+
+	if _, err := transport.Score(context.Background(), pod, []*corev1.Node{node}, []bool{true}); err == nil {
+		t.Fatal("expected Score to return an error once the stream has closed, got nil")
+	}
+}
@@ -0,0 +1,30 @@
+package rlpb
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// jsonCodecName is the gRPC content-subtype this package registers under, so
+// a stream negotiates "application/grpc+json" instead of the default
+// "application/grpc+proto".
+const jsonCodecName = "json"
+
+// jsonCodec lets RLRequest/RLResponse travel over a real gRPC stream despite
+// not being proto.Message implementations: grpc-go's default codec requires
+// Marshal/Unmarshal targets to satisfy proto.Message, which plain structs
+// with only protobuf struct tags don't. Registering this codec and
+// requesting it via grpc.CallContentSubtype makes SendMsg/RecvMsg route
+// through json.Marshal/json.Unmarshal instead.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func (jsonCodec) Name() string { return jsonCodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
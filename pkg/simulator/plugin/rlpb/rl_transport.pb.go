@@ -0,0 +1,74 @@
+// rl_transport.pb.go holds the wire types described by rl_transport.proto.
+// This build environment has no protoc/protoc-gen-go toolchain to generate
+// genuine proto.Message implementations (Reset/String/ProtoReflect), so
+// these are plain hand-written structs carried over the wire as JSON by the
+// codec in codec.go rather than protobuf's binary encoding. The
+// `protobuf:"..."` tags are kept for documentation against the .proto
+// source but aren't load-bearing here; the `json:"..."` tags are what
+// actually drive (de)serialization.
+package rlpb
+
+// RLRequest carries one scheduling cycle's PreScore payload to the RL
+// service. See rl_transport.proto for field documentation.
+type RLRequest struct {
+	CycleId   string   `protobuf:"bytes,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	Pod       []byte   `protobuf:"bytes,2,opt,name=pod,proto3" json:"pod,omitempty"`
+	Nodes     [][]byte `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	ValidMask []bool   `protobuf:"varint,4,rep,packed,name=valid_mask,json=validMask,proto3" json:"valid_mask,omitempty"`
+}
+
+func (x *RLRequest) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+func (x *RLRequest) GetPod() []byte {
+	if x != nil {
+		return x.Pod
+	}
+	return nil
+}
+
+func (x *RLRequest) GetNodes() [][]byte {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *RLRequest) GetValidMask() []bool {
+	if x != nil {
+		return x.ValidMask
+	}
+	return nil
+}
+
+// RLResponse carries the node scores for the cycle identified by CycleId.
+type RLResponse struct {
+	CycleId string           `protobuf:"bytes,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	Scores  map[string]int64 `protobuf:"bytes,2,rep,name=scores,proto3" json:"scores,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Logits  []float32        `protobuf:"fixed32,3,rep,packed,name=logits,proto3" json:"logits,omitempty"`
+}
+
+func (x *RLResponse) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+func (x *RLResponse) GetScores() map[string]int64 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+func (x *RLResponse) GetLogits() []float32 {
+	if x != nil {
+		return x.Logits
+	}
+	return nil
+}
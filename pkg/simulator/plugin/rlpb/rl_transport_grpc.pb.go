@@ -0,0 +1,82 @@
+// rl_transport_grpc.pb.go holds the RLScorer service stubs described by
+// rl_transport.proto. Hand-written for the same reason as rl_transport.pb.go
+// (no protoc-gen-go-grpc available here): ScoreStream forces the JSON codec
+// from codec.go via grpc.CallContentSubtype so Send/Recv work without
+// RLRequest/RLResponse being proto.Message.
+package rlpb
+
+import (
+	"context"
+
+	grpc "google.golang.org/grpc"
+)
+
+// RLScorerClient is the client API for the RLScorer service.
+type RLScorerClient interface {
+	ScoreStream(ctx context.Context, opts ...grpc.CallOption) (RLScorer_ScoreStreamClient, error)
+}
+
+type rLScorerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewRLScorerClient builds a client for the RLScorer service over cc.
+func NewRLScorerClient(cc grpc.ClientConnInterface) RLScorerClient {
+	return &rLScorerClient{cc}
+}
+
+func (c *rLScorerClient) ScoreStream(ctx context.Context, opts ...grpc.CallOption) (RLScorer_ScoreStreamClient, error) {
+	opts = append(opts, grpc.CallContentSubtype(jsonCodecName))
+	stream, err := c.cc.NewStream(ctx, &_RLScorer_serviceDesc.Streams[0], "/rlscheduler.v1.RLScorer/ScoreStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &rLScorerScoreStreamClient{stream}, nil
+}
+
+// RLScorer_ScoreStreamClient is the bidi stream handle held by callers.
+type RLScorer_ScoreStreamClient interface {
+	Send(*RLRequest) error
+	Recv() (*RLResponse, error)
+	grpc.ClientStream
+}
+
+type rLScorerScoreStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *rLScorerScoreStreamClient) Send(m *RLRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *rLScorerScoreStreamClient) Recv() (*RLResponse, error) {
+	m := new(RLResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RLScorerServer is the server API for the RLScorer service.
+type RLScorerServer interface {
+	ScoreStream(RLScorer_ScoreStreamServer) error
+}
+
+// RLScorer_ScoreStreamServer is the bidi stream handle passed to handlers.
+type RLScorer_ScoreStreamServer interface {
+	Send(*RLResponse) error
+	Recv() (*RLRequest, error)
+	grpc.ServerStream
+}
+
+var _RLScorer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "rlscheduler.v1.RLScorer",
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ScoreStream",
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "rl_transport.proto",
+}